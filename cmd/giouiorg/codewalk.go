@@ -0,0 +1,144 @@
+// SPDX-License-Identifier: Unlicense OR MIT
+
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"encoding/xml"
+	"fmt"
+	"html/template"
+	"io/ioutil"
+	"net/http"
+	"path/filepath"
+	"strconv"
+	"strings"
+
+	"github.com/gomarkdown/markdown"
+)
+
+// codewalkDoc is the root element of a .xml codewalk file under
+// content/codewalks/, modeled after godoc's codewalk format.
+type codewalkDoc struct {
+	XMLName xml.Name       `xml:"codewalk"`
+	Title   string         `xml:"title,attr"`
+	Steps   []codewalkStep `xml:"step"`
+}
+
+// codewalkStep is a single <step> entry: narration in markdown alongside a
+// snippet taken from a file under include/ using the same "path:/START/,/END/"
+// addressing that includeExample uses for regular includes.
+type codewalkStep struct {
+	Title string `xml:"title,attr"`
+	Src   string `xml:"src,attr"`
+	Body  string `xml:",innerxml"`
+
+	Narrative template.HTML `xml:"-"`
+	Code      template.HTML `xml:"-"`
+}
+
+func loadCodewalkDoc(name string) (*codewalkDoc, error) {
+	path := filepath.Join(codewalkRoot, name+".xml")
+	content, err := ioutil.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	cw := new(codewalkDoc)
+	if err := xml.Unmarshal(content, cw); err != nil {
+		return nil, fmt.Errorf("%s: failed to parse codewalk: %v", path, err)
+	}
+	for i := range cw.Steps {
+		step := &cw.Steps[i]
+		step.Narrative = template.HTML(markdown.ToHTML([]byte(step.Body), nil, nil))
+		if step.Src == "" {
+			continue
+		}
+		file, addr := splitSrcAddr(step.Src)
+		// Codewalks have no per-page front matter to carry a highlight:
+		// override, so they always render with the site default rather
+		// than inheriting whatever style an unrelated markdown page last
+		// set.
+		code, err := highlightedInclude(file, addr, defaultHighlightStyle)
+		if err != nil {
+			return nil, fmt.Errorf("%s: %s: %v", path, step.Title, err)
+		}
+		step.Code = code
+	}
+	return cw, nil
+}
+
+// splitSrcAddr splits a step's src="include/foo.go:/START/,/END/" attribute
+// into the include-relative file path and the address understood by
+// includeExample.
+func splitSrcAddr(src string) (file, addr string) {
+	file = strings.TrimPrefix(src, includeRoot+"/")
+	if i := strings.Index(file, ":"); i >= 0 {
+		return file[:i], file[i+1:]
+	}
+	return file, ""
+}
+
+func renderCodewalk(name string) ([]byte, error) {
+	cw, err := loadCodewalkDoc(name)
+	if err != nil {
+		return nil, err
+	}
+	var buf bytes.Buffer
+	if err := codewalkTmpl.ExecuteTemplate(&buf, "root", cw); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+// codewalkFile is one entry of the codewalkFileHandler response: a step's
+// source file path alongside its raw (unhighlighted) content, so the
+// client can scroll the real file instead of just the highlighted excerpt.
+type codewalkFile struct {
+	File    string `json:"file"`
+	Content string `json:"content"`
+}
+
+// codewalkFileHandler serves the raw content of a codewalk step's source
+// file(s) as JSON, for client-side scrolling: GET /codewalk/<name>/file
+// returns every step's file, and /codewalk/<name>/file?step=N narrows that
+// to just step N.
+func codewalkFileHandler() http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		path := strings.TrimPrefix(r.URL.Path, codewalkURL)
+		name := strings.TrimSuffix(path, "/file")
+		if name == path {
+			http.NotFound(w, r)
+			return
+		}
+		cw, err := loadCodewalkDoc(name)
+		if err != nil {
+			http.Error(w, "failed to load codewalk", http.StatusInternalServerError)
+			return
+		}
+		steps := cw.Steps
+		if stepParam := r.URL.Query().Get("step"); stepParam != "" {
+			i, err := strconv.Atoi(stepParam)
+			if err != nil || i < 0 || i >= len(cw.Steps) {
+				http.Error(w, "invalid step", http.StatusBadRequest)
+				return
+			}
+			steps = cw.Steps[i : i+1]
+		}
+		files := make([]codewalkFile, 0, len(steps))
+		for _, step := range steps {
+			if step.Src == "" {
+				// Narrative-only step, same as loadCodewalkDoc skips.
+				continue
+			}
+			file, _ := splitSrcAddr(step.Src)
+			content, err := ioutil.ReadFile(filepath.Join(includeRoot, file))
+			if err != nil {
+				http.Error(w, "failed to read step file", http.StatusInternalServerError)
+				return
+			}
+			files = append(files, codewalkFile{File: file, Content: string(content)})
+		}
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(files)
+	})
+}
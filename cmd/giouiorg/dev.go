@@ -0,0 +1,204 @@
+// SPDX-License-Identifier: Unlicense OR MIT
+
+package main
+
+import (
+	"bytes"
+	"flag"
+	"html/template"
+	"log"
+	"net/http"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+
+	"github.com/fsnotify/fsnotify"
+	"github.com/gorilla/websocket"
+)
+
+// devFlag forces dev mode even when GAE_APPLICATION is set, e.g. for
+// testing the App Engine code paths locally.
+var devFlag = flag.Bool("dev", false, "watch content/include/template for changes and live-reload the browser")
+
+// isDevMode reports whether the server should watch content for changes
+// and inject the live-reload script, instead of serving cached pages.
+func isDevMode() bool {
+	return *devFlag || os.Getenv("GAE_APPLICATION") == ""
+}
+
+func init() {
+	startDevMode()
+}
+
+// startDevMode watches content/, include/ and template/ for changes and
+// pushes a reload to connected browsers once they settle. It's a no-op in
+// production, so the App Engine build pays nothing for it beyond the
+// fsnotify and websocket imports.
+func startDevMode() {
+	if !isDevMode() {
+		return
+	}
+	watcher, err := fsnotify.NewWatcher()
+	if err != nil {
+		log.Printf("dev mode: failed to start file watcher: %v", err)
+		return
+	}
+	for _, dir := range []string{contentRoot, includeRoot, "template"} {
+		if err := watchRecursive(watcher, dir); err != nil {
+			log.Printf("dev mode: failed to watch %s: %v", dir, err)
+		}
+	}
+	go watchLoop(watcher)
+	log.Printf("dev mode: watching %s, %s and template for changes", contentRoot, includeRoot)
+}
+
+func watchRecursive(watcher *fsnotify.Watcher, root string) error {
+	return filepath.Walk(root, func(path string, info os.FileInfo, err error) error {
+		if err != nil {
+			return err
+		}
+		if info.IsDir() {
+			return watcher.Add(path)
+		}
+		return nil
+	})
+}
+
+// watchLoop debounces fsnotify events for ~100ms before reloading, the same
+// way Hugo's watcher/batcher.go coalesces a burst of writes into one
+// rebuild.
+func watchLoop(watcher *fsnotify.Watcher) {
+	const debounce = 100 * time.Millisecond
+	var timer *time.Timer
+	for {
+		select {
+		case event, ok := <-watcher.Events:
+			if !ok {
+				return
+			}
+			if event.Op&(fsnotify.Write|fsnotify.Create|fsnotify.Remove|fsnotify.Rename) == 0 {
+				continue
+			}
+			if timer == nil {
+				timer = time.AfterFunc(debounce, reload)
+			} else {
+				timer.Reset(debounce)
+			}
+		case err, ok := <-watcher.Errors:
+			if !ok {
+				return
+			}
+			log.Printf("dev mode: watcher error: %v", err)
+		}
+	}
+}
+
+// reload re-parses templates and rebuilds the pages cache, then notifies
+// any connected browsers to refresh. It runs on its own goroutine from the
+// debounce timer in watchLoop, concurrently with in-flight requests, so it
+// takes stateMu for the same reason servePage does.
+func reload() {
+	stateMu.Lock()
+	defer stateMu.Unlock()
+	if err := reparseTemplates(); err != nil {
+		log.Printf("dev mode: failed to re-parse templates: %v", err)
+		return
+	}
+	if err := loadDocs(contentRoot); err != nil {
+		log.Printf("dev mode: failed to reload content: %v", err)
+		return
+	}
+	resetHighlightCache()
+	broadcastReload()
+}
+
+func reparseTemplates() error {
+	doc, err := template.ParseFiles(filepath.Join("template", "page.tmpl"), filepath.Join("template", "root.tmpl"))
+	if err != nil {
+		return err
+	}
+	codewalk, err := template.ParseFiles(filepath.Join("template", "codewalk.tmpl"), filepath.Join("template", "root.tmpl"))
+	if err != nil {
+		return err
+	}
+	taxonomy, err := template.ParseFiles(filepath.Join("template", "taxonomy.tmpl"), filepath.Join("template", "root.tmpl"))
+	if err != nil {
+		return err
+	}
+	term, err := template.ParseFiles(filepath.Join("template", "term.tmpl"), filepath.Join("template", "root.tmpl"))
+	if err != nil {
+		return err
+	}
+	search, err := template.ParseFiles(filepath.Join("template", "search.tmpl"), filepath.Join("template", "root.tmpl"))
+	if err != nil {
+		return err
+	}
+	docTmpl, codewalkTmpl, taxonomyTmpl, termTmpl, searchTmpl = doc, codewalk, taxonomy, term, search
+	return nil
+}
+
+var liveReloadUpgrader = websocket.Upgrader{
+	CheckOrigin: func(r *http.Request) bool { return true },
+}
+
+var liveReloadClients = struct {
+	sync.Mutex
+	conns map[*websocket.Conn]bool
+}{conns: make(map[*websocket.Conn]bool)}
+
+// liveReloadHandler upgrades /_livereload to a WebSocket that the browser
+// snippet injected by injectLiveReload connects to.
+func liveReloadHandler() http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		conn, err := liveReloadUpgrader.Upgrade(w, r, nil)
+		if err != nil {
+			log.Printf("dev mode: livereload upgrade failed: %v", err)
+			return
+		}
+		liveReloadClients.Lock()
+		liveReloadClients.conns[conn] = true
+		liveReloadClients.Unlock()
+		defer func() {
+			liveReloadClients.Lock()
+			delete(liveReloadClients.conns, conn)
+			liveReloadClients.Unlock()
+			conn.Close()
+		}()
+		for {
+			if _, _, err := conn.ReadMessage(); err != nil {
+				return
+			}
+		}
+	})
+}
+
+func broadcastReload() {
+	liveReloadClients.Lock()
+	defer liveReloadClients.Unlock()
+	for conn := range liveReloadClients.conns {
+		if err := conn.WriteMessage(websocket.TextMessage, []byte("reload")); err != nil {
+			conn.Close()
+			delete(liveReloadClients.conns, conn)
+		}
+	}
+}
+
+const liveReloadScript = `<script>(function() {
+	var proto = location.protocol === "https:" ? "wss://" : "ws://";
+	var ws = new WebSocket(proto + location.host + "/_livereload");
+	ws.onmessage = function() { location.reload(); };
+})();</script>`
+
+// injectLiveReload appends the live-reload snippet just before </body>, or
+// at the end of content without one.
+func injectLiveReload(content []byte) []byte {
+	if i := bytes.LastIndex(content, []byte("</body>")); i >= 0 {
+		var buf bytes.Buffer
+		buf.Write(content[:i])
+		buf.WriteString(liveReloadScript)
+		buf.Write(content[i:])
+		return buf.Bytes()
+	}
+	return append(content, []byte(liveReloadScript)...)
+}
@@ -0,0 +1,177 @@
+// SPDX-License-Identifier: Unlicense OR MIT
+
+package main
+
+import (
+	"bytes"
+	"fmt"
+	"html/template"
+	"net/http"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"sync"
+
+	"github.com/alecthomas/chroma"
+	chromahtml "github.com/alecthomas/chroma/formatters/html"
+	"github.com/alecthomas/chroma/lexers"
+	"github.com/alecthomas/chroma/styles"
+)
+
+// defaultHighlightStyle is used when a page or shortcode doesn't request one
+// explicitly via front matter or a highlight= argument.
+const defaultHighlightStyle = "monokai"
+
+type highlightKey struct {
+	path  string
+	addr  string
+	style string
+}
+
+var highlightCache = struct {
+	sync.Mutex
+	m map[highlightKey]template.HTML
+}{m: make(map[highlightKey]template.HTML)}
+
+// resetHighlightCache drops every cached highlighted fragment. dev.go's
+// reload calls this alongside loadDocs so an edit under include/ shows up
+// immediately instead of serving a stale cached rendering for the rest of
+// the process's life.
+func resetHighlightCache() {
+	highlightCache.Lock()
+	highlightCache.m = make(map[highlightKey]template.HTML)
+	highlightCache.Unlock()
+}
+
+// highlightedInclude is includeExample plus Chroma syntax highlighting. addr
+// carries the regular "/START/,/END/" address optionally followed by
+// ";key=val" options: "lang" overrides the extension-derived language and
+// "hl_lines" highlights a set of lines, e.g. "/START/,/END/;hl_lines=3-7".
+func highlightedInclude(path, addr, style string) (template.HTML, error) {
+	base, opts := splitAddrOptions(addr)
+	content, err := includeExample(path, base)
+	if err != nil {
+		return "", err
+	}
+	if style == "" {
+		style = defaultHighlightStyle
+	}
+	key := highlightKey{path, addr, style}
+	highlightCache.Lock()
+	cached, ok := highlightCache.m[key]
+	highlightCache.Unlock()
+	if ok {
+		return cached, nil
+	}
+	lang := opts["lang"]
+	if lang == "" {
+		lang = langForPath(path)
+	}
+	hlLines, err := parseHLLines(opts["hl_lines"])
+	if err != nil {
+		return "", err
+	}
+	out, err := highlight(lang, content, style, hlLines)
+	if err != nil {
+		return "", err
+	}
+	highlightCache.Lock()
+	highlightCache.m[key] = out
+	highlightCache.Unlock()
+	return out, nil
+}
+
+func highlight(lang string, code []byte, style string, hlLines [][2]int) (template.HTML, error) {
+	lexer := lexers.Get(lang)
+	if lexer == nil {
+		lexer = lexers.Fallback
+	}
+	lexer = chroma.Coalesce(lexer)
+	s := styles.Get(style)
+	if s == nil {
+		s = styles.Fallback
+	}
+	formatter := chromahtml.New(chromahtml.WithClasses(true), chromahtml.HighlightLines(hlLines))
+	it, err := lexer.Tokenise(nil, string(code))
+	if err != nil {
+		return "", fmt.Errorf("highlight: %v", err)
+	}
+	var buf bytes.Buffer
+	if err := formatter.Format(&buf, s, it); err != nil {
+		return "", fmt.Errorf("highlight: %v", err)
+	}
+	return template.HTML(buf.String()), nil
+}
+
+func langForPath(path string) string {
+	switch filepath.Ext(path) {
+	case ".go":
+		return "go"
+	case ".xml":
+		return "xml"
+	case ".sh":
+		return "bash"
+	default:
+		return ""
+	}
+}
+
+// splitAddrOptions splits an include address like "/START/,/END/;hl_lines=3-7"
+// into the base address understood by includeExample and a set of
+// highlighting options.
+func splitAddrOptions(addr string) (base string, opts map[string]string) {
+	parts := strings.Split(addr, ";")
+	opts = make(map[string]string)
+	for _, p := range parts[1:] {
+		kv := strings.SplitN(p, "=", 2)
+		if len(kv) == 2 {
+			opts[strings.TrimSpace(kv[0])] = strings.TrimSpace(kv[1])
+		}
+	}
+	return parts[0], opts
+}
+
+func parseHLLines(s string) ([][2]int, error) {
+	if s == "" {
+		return nil, nil
+	}
+	var ranges [][2]int
+	for _, part := range strings.Split(s, ",") {
+		part = strings.TrimSpace(part)
+		if part == "" {
+			continue
+		}
+		bounds := strings.SplitN(part, "-", 2)
+		start, err := strconv.Atoi(bounds[0])
+		if err != nil {
+			return nil, fmt.Errorf("invalid hl_lines range %q", part)
+		}
+		end := start
+		if len(bounds) == 2 {
+			if end, err = strconv.Atoi(bounds[1]); err != nil {
+				return nil, fmt.Errorf("invalid hl_lines range %q", part)
+			}
+		}
+		ranges = append(ranges, [2]int{start, end})
+	}
+	return ranges, nil
+}
+
+// chromaCSSHandler serves the stylesheet matching the tokens highlight
+// produces, e.g. mounted at /assets/chroma.css.
+func chromaCSSHandler(style string) http.Handler {
+	if style == "" {
+		style = defaultHighlightStyle
+	}
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		s := styles.Get(style)
+		if s == nil {
+			s = styles.Fallback
+		}
+		formatter := chromahtml.New(chromahtml.WithClasses(true))
+		w.Header().Set("Content-Type", "text/css; charset=utf-8")
+		if err := formatter.WriteCSS(w, s); err != nil {
+			http.Error(w, "failed to render stylesheet", http.StatusInternalServerError)
+		}
+	})
+}
@@ -0,0 +1,59 @@
+// SPDX-License-Identifier: Unlicense OR MIT
+
+package main
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestParseHLLines(t *testing.T) {
+	tests := []struct {
+		in      string
+		want    [][2]int
+		wantErr bool
+	}{
+		{"", nil, false},
+		{"3", [][2]int{{3, 3}}, false},
+		{"3-7", [][2]int{{3, 7}}, false},
+		{"1,3-7,10", [][2]int{{1, 1}, {3, 7}, {10, 10}}, false},
+		{" 3 , 5-6 ", [][2]int{{3, 3}, {5, 6}}, false},
+		{"x-7", nil, true},
+		{"3-y", nil, true},
+	}
+	for _, tt := range tests {
+		got, err := parseHLLines(tt.in)
+		if (err != nil) != tt.wantErr {
+			t.Errorf("parseHLLines(%q) error = %v, wantErr %v", tt.in, err, tt.wantErr)
+			continue
+		}
+		if err != nil {
+			continue
+		}
+		if !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("parseHLLines(%q) = %#v, want %#v", tt.in, got, tt.want)
+		}
+	}
+}
+
+func TestSplitAddrOptions(t *testing.T) {
+	tests := []struct {
+		in       string
+		wantBase string
+		wantOpts map[string]string
+	}{
+		{"", "", map[string]string{}},
+		{"/START/,/END/", "/START/,/END/", map[string]string{}},
+		{"/START/,/END/;hl_lines=3-7", "/START/,/END/", map[string]string{"hl_lines": "3-7"}},
+		{"/START/,/END/;lang=go;hl_lines=3-7", "/START/,/END/", map[string]string{"lang": "go", "hl_lines": "3-7"}},
+	}
+	for _, tt := range tests {
+		base, opts := splitAddrOptions(tt.in)
+		if base != tt.wantBase {
+			t.Errorf("splitAddrOptions(%q) base = %q, want %q", tt.in, base, tt.wantBase)
+		}
+		if !reflect.DeepEqual(opts, tt.wantOpts) {
+			t.Errorf("splitAddrOptions(%q) opts = %#v, want %#v", tt.in, opts, tt.wantOpts)
+		}
+	}
+}
@@ -0,0 +1,203 @@
+// SPDX-License-Identifier: Unlicense OR MIT
+
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"encoding/xml"
+	"fmt"
+	"html/template"
+	"io"
+	"net/http"
+	"sort"
+	"time"
+)
+
+// renderedPage is the input to an OutputFormat's Render func: a page's front
+// matter plus its markdown body already converted to HTML.
+type renderedPage struct {
+	Path    string
+	Front   frontMatter
+	Content template.HTML
+}
+
+// OutputFormat is a renderer selected by URL suffix, mirroring the
+// virtualized output-format model Hugo uses: the same page content can be
+// emitted as HTML, an RSS item, a JSON Feed item, and so on.
+type OutputFormat struct {
+	Name      string
+	MediaType string
+	Suffix    string // URL suffix that selects this format; "" for the default.
+	Render    func(renderedPage) ([]byte, error)
+}
+
+// outputFormats is initialized from a var initializer, not an init func:
+// page.go's own init calls loadDocs before any file's init func is
+// guaranteed to have run, but var initializers always run first.
+var outputFormats = map[string]OutputFormat{
+	"html": {
+		Name:      "html",
+		MediaType: "text/html; charset=utf-8",
+		Suffix:    "",
+		Render:    renderHTMLFormat,
+	},
+	"rss": {
+		Name:      "rss",
+		MediaType: "application/rss+xml; charset=utf-8",
+		Suffix:    ".xml",
+		Render:    renderRSSFormat,
+	},
+	"json": {
+		Name:      "json",
+		MediaType: "application/feed+json; charset=utf-8",
+		Suffix:    ".json",
+		Render:    renderJSONFeedFormat,
+	},
+}
+
+// registerOutputFormat lets additional output formats be added without
+// touching pageHandler's dispatch logic.
+func registerOutputFormat(f OutputFormat) {
+	outputFormats[f.Name] = f
+}
+
+func renderHTMLFormat(rp renderedPage) ([]byte, error) {
+	args := struct {
+		Front   frontMatter
+		Content template.HTML
+	}{rp.Front, rp.Content}
+	var buf bytes.Buffer
+	if err := docTmpl.ExecuteTemplate(&buf, "root", args); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+func renderRSSFormat(rp renderedPage) ([]byte, error) {
+	item := rssItem(rp)
+	var buf bytes.Buffer
+	buf.WriteString(xml.Header)
+	fmt.Fprintf(&buf, "<rss version=\"2.0\"><channel><title>%s</title>\n", template.HTMLEscapeString(rp.Front.Title))
+	writeRSSItem(&buf, item)
+	buf.WriteString("</channel></rss>\n")
+	return buf.Bytes(), nil
+}
+
+func renderJSONFeedFormat(rp renderedPage) ([]byte, error) {
+	feed := jsonFeed{
+		Version: "https://jsonfeed.org/version/1.1",
+		Title:   rp.Front.Title,
+		Items:   []jsonFeedItem{jsonFeedItemFor(rp)},
+	}
+	return json.MarshalIndent(feed, "", "  ")
+}
+
+type rssFeedItem struct {
+	Path    string
+	Title   string
+	Content template.HTML
+	Date    time.Time
+}
+
+func rssItem(rp renderedPage) rssFeedItem {
+	date, _ := time.Parse("2006-01-02", rp.Front.Date)
+	return rssFeedItem{Path: rp.Path, Title: rp.Front.Title, Content: rp.Content, Date: date}
+}
+
+func writeRSSItem(w io.Writer, item rssFeedItem) {
+	fmt.Fprintf(w, "<item><title>%s</title><link>https://gioui.org%s</link><guid>https://gioui.org%s</guid>",
+		template.HTMLEscapeString(item.Title), item.Path, item.Path)
+	if !item.Date.IsZero() {
+		fmt.Fprintf(w, "<pubDate>%s</pubDate>", item.Date.Format(time.RFC1123Z))
+	}
+	fmt.Fprintf(w, "<description><![CDATA[%s]]></description></item>\n", item.Content)
+}
+
+type jsonFeed struct {
+	Version string         `json:"version"`
+	Title   string         `json:"title"`
+	Items   []jsonFeedItem `json:"items"`
+}
+
+type jsonFeedItem struct {
+	ID            string `json:"id"`
+	URL           string `json:"url"`
+	Title         string `json:"title"`
+	ContentHTML   string `json:"content_html"`
+	DatePublished string `json:"date_published,omitempty"`
+}
+
+func jsonFeedItemFor(rp renderedPage) jsonFeedItem {
+	return jsonFeedItem{
+		ID:            "https://gioui.org" + rp.Path,
+		URL:           "https://gioui.org" + rp.Path,
+		Title:         rp.Front.Title,
+		ContentHTML:   string(rp.Content),
+		DatePublished: rp.Front.Date,
+	}
+}
+
+// datedPages collects every page whose front matter sets date:, for the
+// site-wide /feed.xml aggregate. It's rebuilt alongside the taxonomy and
+// search indexes whenever loadDocTree walks content/.
+var datedPages []renderedPage
+
+func resetDatedPages() {
+	datedPages = nil
+}
+
+// recordDatedPage adds rp to the feed, replacing any existing entry for the
+// same path (loadMarkdown runs once per output format a page has).
+func recordDatedPage(rp renderedPage) {
+	for i, existing := range datedPages {
+		if existing.Path == rp.Path {
+			datedPages[i] = rp
+			return
+		}
+	}
+	datedPages = append(datedPages, rp)
+}
+
+// feedHandler serves the site-wide /feed.xml RSS feed, aggregating every
+// page with a date: in its front matter, newest first.
+func feedHandler() http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		stateMu.Lock()
+		items := make([]rssFeedItem, len(datedPages))
+		for i, rp := range datedPages {
+			items[i] = rssItem(rp)
+		}
+		stateMu.Unlock()
+		sort.Slice(items, func(i, j int) bool { return items[i].Date.After(items[j].Date) })
+		w.Header().Set("Content-Type", outputFormats["rss"].MediaType)
+		fmt.Fprint(w, xml.Header)
+		fmt.Fprint(w, "<rss version=\"2.0\"><channel><title>Gio - immediate mode GUI in Go</title>\n")
+		for _, item := range items {
+			writeRSSItem(w, item)
+		}
+		fmt.Fprint(w, "</channel></rss>\n")
+	})
+}
+
+// sitemapHandler serves /sitemap.xml, listing every known HTML page.
+func sitemapHandler() http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/xml; charset=utf-8")
+		fmt.Fprint(w, xml.Header)
+		fmt.Fprint(w, `<urlset xmlns="http://www.sitemaps.org/schemas/sitemap/0.9">`+"\n")
+		stateMu.Lock()
+		keys := make([]pageKey, 0, len(pages))
+		for key := range pages {
+			keys = append(keys, key)
+		}
+		stateMu.Unlock()
+		for _, key := range keys {
+			if key.format != "html" {
+				continue
+			}
+			fmt.Fprintf(w, "<url><loc>https://gioui.org%s</loc></url>\n", key.path)
+		}
+		fmt.Fprint(w, "</urlset>\n")
+	})
+}
@@ -16,6 +16,7 @@ import (
 	"path/filepath"
 	"regexp"
 	"strings"
+	"sync"
 
 	"github.com/gomarkdown/markdown"
 	"github.com/gomarkdown/markdown/parser"
@@ -23,7 +24,30 @@ import (
 )
 
 type frontMatter struct {
-	Title string `yaml:"title"`
+	Title      string   `yaml:"title"`
+	Highlight  string   `yaml:"highlight"`
+	Tags       []string `yaml:"tags"`
+	Categories []string `yaml:"categories"`
+	Date       string   `yaml:"date"`
+	Outputs    []string `yaml:"outputs"`
+}
+
+// outputs returns the output format names a page opts into, defaulting to
+// just "html" when front matter doesn't set outputs:.
+func (f frontMatter) outputs() []string {
+	if len(f.Outputs) == 0 {
+		return []string{"html"}
+	}
+	return f.Outputs
+}
+
+func (f frontMatter) hasOutput(name string) bool {
+	for _, o := range f.outputs() {
+		if o == name {
+			return true
+		}
+	}
+	return false
 }
 
 type page struct {
@@ -31,15 +55,33 @@ type page struct {
 	Content []byte
 }
 
+// pageKey identifies a cached page by its URL path and output format, e.g.
+// {"/blog/post", "rss"} for /blog/post.xml.
+type pageKey struct {
+	path   string
+	format string
+}
+
 var (
-	docTmpl   *template.Template
-	pages     = make(map[string][]byte)
-	errNoPage = errors.New("no such page")
+	docTmpl      *template.Template
+	codewalkTmpl *template.Template
+	pages        = make(map[pageKey][]byte)
+	errNoPage    = errors.New("no such page")
 )
 
+// stateMu serializes every read or write of the content state that's
+// rebuilt as a unit by loadDocs: pages, taxonomies, searchIdx, datedPages,
+// and the *Tmpl globals dev.go's reload swaps out from its own goroutine.
+// In GAE mode it's only ever touched sequentially at startup, but dev mode
+// calls loadMarkdown straight from servePage on every request, concurrently
+// with the file-watcher's reload goroutine, so those paths take the lock.
+var stateMu sync.Mutex
+
 const (
-	contentRoot = "content"
-	includeRoot = "include"
+	contentRoot  = "content"
+	includeRoot  = "include"
+	codewalkRoot = "content/codewalks"
+	codewalkURL  = "/codewalk/"
 )
 
 func init() {
@@ -47,50 +89,130 @@ func init() {
 		filepath.Join("template", "page.tmpl"),
 		filepath.Join("template", "root.tmpl"),
 	))
+	codewalkTmpl = template.Must(template.ParseFiles(
+		filepath.Join("template", "codewalk.tmpl"),
+		filepath.Join("template", "root.tmpl"),
+	))
 	if err := loadDocs(filepath.Join(contentRoot)); err != nil {
 		log.Fatal(err)
 	}
 }
 
 func loadDocs(root string) error {
+	resetTaxonomies()
+	resetSearchIndex()
+	err := loadDocTree(root)
+	if err == nil && !skipSearchIndexing {
+		if idx, ok := searchIdx.(*memIndex); ok {
+			if err := saveIndexSnapshot(idx, searchSnapshotPath); err != nil {
+				log.Printf("failed to save search index snapshot: %v", err)
+			}
+		}
+	}
+	return err
+}
+
+func loadDocTree(root string) error {
+	resetDatedPages()
 	return filepath.Walk(root, func(path string, info os.FileInfo, err error) error {
 		if err != nil {
 			return err
 		}
-		if info.IsDir() || filepath.Ext(path) != ".md" {
+		if info.IsDir() {
 			return nil
 		}
-		name := path[len(root):]
-		name = name[:len(name)-len(".md")]
-		content, err := loadMarkdown(name)
-		if err != nil {
-			return err
+		switch filepath.Ext(path) {
+		case ".md":
+			name := path[len(root):]
+			name = name[:len(name)-len(".md")]
+			front, err := peekFrontMatter(path)
+			if err != nil {
+				return err
+			}
+			for _, format := range front.outputs() {
+				content, err := loadMarkdown(name, format)
+				if err != nil {
+					return err
+				}
+				pages[pageKey{name, format}] = content
+			}
+		case ".xml":
+			if !strings.HasPrefix(path, codewalkRoot+string(filepath.Separator)) {
+				return nil
+			}
+			name := path[len(codewalkRoot):]
+			name = name[:len(name)-len(".xml")]
+			content, err := renderCodewalk(name)
+			if err != nil {
+				return err
+			}
+			pages[pageKey{codewalkURL + strings.TrimPrefix(name, "/"), "html"}] = content
 		}
-		pages[name] = content
 		return nil
 	})
 }
 
+// peekFrontMatter reads a markdown file and parses its front matter to know
+// which output formats it opts into, before loadMarkdown does the full
+// render per format.
+func peekFrontMatter(path string) (frontMatter, error) {
+	content, err := ioutil.ReadFile(path)
+	if err != nil {
+		return frontMatter{}, err
+	}
+	pg, err := loadPage(content)
+	if err != nil {
+		return frontMatter{}, fmt.Errorf("%s: failed to parse front matter: %v", path, err)
+	}
+	return pg.Front, nil
+}
+
 func servePage(w io.Writer, path string) error {
-	var page []byte
+	stateMu.Lock()
+	defer stateMu.Unlock()
+	base, format := splitFormatSuffix(path)
+	var content []byte
 	if os.Getenv("GAE_APPLICATION") != "" {
-		p, ok := pages[path]
+		p, ok := pages[pageKey{base, format}]
 		if !ok {
 			return errNoPage
 		}
-		page = p
+		content = p
+	} else if name := strings.TrimPrefix(base, codewalkURL); name != base {
+		if format != "html" {
+			return errNoPage
+		}
+		p, err := renderCodewalk(name)
+		if err != nil {
+			return err
+		}
+		content = p
 	} else {
-		p, err := loadMarkdown(path)
+		p, err := loadMarkdown(base, format)
 		if err != nil {
 			return err
 		}
-		page = p
+		content = p
+	}
+	if format == "html" && isDevMode() {
+		content = injectLiveReload(content)
 	}
-	_, err := w.Write(page)
+	_, err := w.Write(content)
 	return err
 }
 
-func loadMarkdown(url string) ([]byte, error) {
+// splitFormatSuffix maps a URL path to the output format it requests and
+// the page path the format applies to, e.g. "/post.xml" -> ("/post", "rss").
+func splitFormatSuffix(path string) (base, format string) {
+	for _, of := range outputFormats {
+		if of.Suffix != "" && strings.HasSuffix(path, of.Suffix) {
+			return strings.TrimSuffix(path, of.Suffix), of.Name
+		}
+	}
+	return path, "html"
+}
+
+func loadMarkdown(url, format string) ([]byte, error) {
 	path := filepath.Join(contentRoot, url+".md")
 	content, err := ioutil.ReadFile(path)
 	if err != nil {
@@ -103,24 +225,33 @@ func loadMarkdown(url string) ([]byte, error) {
 	if page.Front.Title == "" {
 		page.Front.Title = "Gio - immediate mode GUI in Go"
 	}
-	mdp := parser.NewWithExtensions(parser.CommonExtensions | parser.Includes | parser.Attributes)
-	mdp.Opts.ReadIncludeFn = func(from, path string, addr []byte) []byte {
-		content, err := includeExample(path, string(addr))
-		if err != nil {
-			content = []byte(err.Error())
-		}
-		return content
+	if !page.Front.hasOutput(format) {
+		return nil, errNoPage
 	}
-	html := markdown.ToHTML(page.Content, mdp, nil)
-	args := struct {
-		Front   frontMatter
-		Content template.HTML
-	}{page.Front, template.HTML(html)}
-	var buf bytes.Buffer
-	if err := docTmpl.ExecuteTemplate(&buf, "root", args); err != nil {
-		return nil, err
+	of, ok := outputFormats[format]
+	if !ok {
+		return nil, fmt.Errorf("%s: unknown output format %q", path, format)
+	}
+	style := page.Front.Highlight
+	if style == "" {
+		style = defaultHighlightStyle
+	}
+	recordTaxonomies(url, page.Front, excerpt(page.Content))
+	if !skipSearchIndexing {
+		searchIdx.Index(url, page.Front.Title, toPlainText(page.Content))
+	}
+	if page.Front.Date != "" {
+		recordDatedPage(renderedPage{Path: url, Front: page.Front})
+	}
+	expanded := expandShortcodes(page.Content, style)
+	mdp := parser.NewWithExtensions(parser.CommonExtensions | parser.Attributes)
+	html := markdown.ToHTML(expanded, mdp, nil)
+	rp := renderedPage{Path: url, Front: page.Front, Content: template.HTML(html)}
+	out, err := of.Render(rp)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %v", path, err)
 	}
-	return buf.Bytes(), nil
+	return out, nil
 }
 
 func includeExample(path string, addr string) ([]byte, error) {
@@ -0,0 +1,408 @@
+// SPDX-License-Identifier: Unlicense OR MIT
+
+package main
+
+import (
+	"encoding/gob"
+	"html/template"
+	"io"
+	"net/http"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strings"
+
+	"github.com/gomarkdown/markdown"
+)
+
+// SearchResult is a single ranked hit returned by a searchBackend.
+type SearchResult struct {
+	Path    string
+	Title   string
+	Excerpt string
+}
+
+// searchBackend is the interface the /search handler talks to, so the
+// default in-memory index can later be swapped for something like Bleve
+// without touching the handler.
+type searchBackend interface {
+	Index(path, title, text string)
+	Search(query string) []SearchResult
+}
+
+// Hit records one term's occurrences within a single page.
+type Hit struct {
+	Path       string
+	TitleMatch bool
+	Positions  []int
+}
+
+// memIndex is the default searchBackend: an in-memory posting list, gob
+// snapshotted to disk so App Engine cold starts don't have to re-tokenize
+// every page. Fields are exported so encoding/gob can see them.
+type memIndex struct {
+	Postings map[string][]Hit
+	Titles   map[string]string
+	Plain    map[string]string
+}
+
+func newMemIndex() *memIndex {
+	return &memIndex{
+		Postings: make(map[string][]Hit),
+		Titles:   make(map[string]string),
+		Plain:    make(map[string]string),
+	}
+}
+
+func (idx *memIndex) Index(path, title, text string) {
+	idx.removePath(path)
+	idx.Titles[path] = title
+	idx.Plain[path] = text
+	titleTerms := make(map[string]bool)
+	for _, t := range tokenize(title) {
+		titleTerms[t] = true
+	}
+	positions := make(map[string][]int)
+	for i, tok := range tokenize(text) {
+		positions[tok] = append(positions[tok], i)
+	}
+	for term, pos := range positions {
+		idx.Postings[term] = append(idx.Postings[term], Hit{
+			Path:       path,
+			TitleMatch: titleTerms[term],
+			Positions:  pos,
+		})
+	}
+}
+
+// removePath drops any existing entries for path before it's re-indexed, so
+// indexing a page more than once (once per output format, or on every
+// dev-mode request) doesn't double its term frequencies.
+func (idx *memIndex) removePath(path string) {
+	delete(idx.Titles, path)
+	delete(idx.Plain, path)
+	for term, hits := range idx.Postings {
+		filtered := hits[:0]
+		for _, h := range hits {
+			if h.Path != path {
+				filtered = append(filtered, h)
+			}
+		}
+		if len(filtered) == 0 {
+			delete(idx.Postings, term)
+		} else {
+			idx.Postings[term] = filtered
+		}
+	}
+}
+
+func (idx *memIndex) Search(q string) []SearchResult {
+	clauses := parseQuery(q)
+	if len(clauses) == 0 {
+		return nil
+	}
+	matches := make(map[string]bool)
+	for i, cl := range clauses {
+		hit := idx.matchClause(cl)
+		if i == 0 {
+			for p := range hit {
+				matches[p] = true
+			}
+			continue
+		}
+		for p := range matches {
+			if !hit[p] {
+				delete(matches, p)
+			}
+		}
+	}
+	results := make([]SearchResult, 0, len(matches))
+	for path := range matches {
+		results = append(results, SearchResult{
+			Path:    path,
+			Title:   idx.Titles[path],
+			Excerpt: idx.excerpt(path, clauses),
+		})
+	}
+	sort.Slice(results, func(i, j int) bool {
+		si, sj := idx.score(results[i].Path, clauses), idx.score(results[j].Path, clauses)
+		if si != sj {
+			return si > sj
+		}
+		return results[i].Title < results[j].Title
+	})
+	return results
+}
+
+// score ranks a page by (title-match, term frequency, page depth), shallower
+// and title-matching pages first.
+func (idx *memIndex) score(path string, clauses []queryClause) float64 {
+	var tf float64
+	titleMatch := false
+	for _, cl := range clauses {
+		for _, term := range cl.terms {
+			for _, hit := range idx.Postings[term] {
+				if hit.Path != path {
+					continue
+				}
+				tf += float64(len(hit.Positions))
+				if hit.TitleMatch {
+					titleMatch = true
+				}
+			}
+		}
+	}
+	score := tf
+	if titleMatch {
+		score += 1000
+	}
+	score -= float64(strings.Count(path, "/"))
+	return score
+}
+
+// excerpt returns up to 40 characters of plain text on either side of the
+// first matching term, for display in search results.
+func (idx *memIndex) excerpt(path string, clauses []queryClause) string {
+	text := idx.Plain[path]
+	lower := strings.ToLower(text)
+	pos := -1
+	for _, cl := range clauses {
+		for _, term := range cl.terms {
+			if i := strings.Index(lower, term); i >= 0 && (pos == -1 || i < pos) {
+				pos = i
+			}
+		}
+	}
+	if pos == -1 {
+		if len(text) > 80 {
+			return text[:80] + "…"
+		}
+		return text
+	}
+	start, end := pos-40, pos+40
+	if start < 0 {
+		start = 0
+	}
+	if end > len(text) {
+		end = len(text)
+	}
+	return text[start:end]
+}
+
+func (idx *memIndex) matchClause(cl queryClause) map[string]bool {
+	matches := make(map[string]bool)
+	switch cl.kind {
+	case termQuery, orQuery:
+		for _, term := range cl.terms {
+			for _, hit := range idx.Postings[term] {
+				matches[hit.Path] = true
+			}
+		}
+	case phraseQuery:
+		for path, text := range idx.Plain {
+			if containsPhrase(tokenize(text), cl.terms) {
+				matches[path] = true
+			}
+		}
+	}
+	return matches
+}
+
+func containsPhrase(tokens, phrase []string) bool {
+	if len(phrase) == 0 || len(phrase) > len(tokens) {
+		return false
+	}
+	for i := 0; i+len(phrase) <= len(tokens); i++ {
+		match := true
+		for j, term := range phrase {
+			if tokens[i+j] != term {
+				match = false
+				break
+			}
+		}
+		if match {
+			return true
+		}
+	}
+	return false
+}
+
+var tokenRE = regexp.MustCompile(`[a-zA-Z0-9]+`)
+
+func tokenize(text string) []string {
+	return tokenRE.FindAllString(strings.ToLower(text), -1)
+}
+
+var htmlTagRE = regexp.MustCompile(`<[^>]*>`)
+
+// toPlainText renders markdown to HTML and strips tags, giving a rough
+// plain-text version of a page suitable for indexing.
+func toPlainText(mdSource []byte) string {
+	html := markdown.ToHTML(mdSource, nil, nil)
+	return strings.TrimSpace(htmlTagRE.ReplaceAllString(string(html), " "))
+}
+
+type queryKind int
+
+const (
+	termQuery queryKind = iota
+	orQuery
+	phraseQuery
+)
+
+type queryClause struct {
+	kind  queryKind
+	terms []string
+}
+
+// parseQuery parses a search query into AND/OR/phrase clauses: clauses are
+// ANDed together; "a OR b" groups alternatives; "a b c" in quotes matches
+// only that exact sequence of terms.
+func parseQuery(q string) []queryClause {
+	fields := splitQueryFields(q)
+	var clauses []queryClause
+	for i := 0; i < len(fields); {
+		f := fields[i]
+		if strings.HasPrefix(f, `"`) {
+			phrase := tokenize(strings.Trim(f, `"`))
+			if len(phrase) > 0 {
+				clauses = append(clauses, queryClause{phraseQuery, phrase})
+			}
+			i++
+			continue
+		}
+		group := []string{strings.ToLower(f)}
+		j := i
+		for j+2 < len(fields) && strings.EqualFold(fields[j+1], "OR") {
+			group = append(group, strings.ToLower(fields[j+2]))
+			j += 2
+		}
+		if len(group) > 1 {
+			clauses = append(clauses, queryClause{orQuery, group})
+		} else {
+			clauses = append(clauses, queryClause{termQuery, group})
+		}
+		i = j + 1
+	}
+	return clauses
+}
+
+func splitQueryFields(q string) []string {
+	var fields []string
+	var cur strings.Builder
+	inQuote := false
+	for _, r := range q {
+		switch {
+		case r == '"':
+			cur.WriteRune(r)
+			inQuote = !inQuote
+		case r == ' ' && !inQuote:
+			if cur.Len() > 0 {
+				fields = append(fields, cur.String())
+				cur.Reset()
+			}
+		default:
+			cur.WriteRune(r)
+		}
+	}
+	if cur.Len() > 0 {
+		fields = append(fields, cur.String())
+	}
+	return fields
+}
+
+const searchSnapshotPath = "search.gob"
+
+var searchTmpl *template.Template
+
+// searchIdx and skipSearchIndexing are initialized from a var initializer
+// rather than an init func: loadDocs (called from page.go's own init) reads
+// searchIdx, and package-level var initializers are guaranteed to run
+// before any init func regardless of file order, while init funcs across
+// files only run in (unspecified-ish) lexical order.
+var searchIdx, skipSearchIndexing = newSearchIndex()
+
+func newSearchIndex() (searchBackend, bool) {
+	if os.Getenv("GAE_APPLICATION") != "" {
+		if idx, err := loadIndexSnapshot(searchSnapshotPath); err == nil {
+			return idx, true
+		}
+	}
+	return newMemIndex(), false
+}
+
+func init() {
+	searchTmpl = template.Must(template.ParseFiles(
+		filepath.Join("template", "search.tmpl"),
+		filepath.Join("template", "root.tmpl"),
+	))
+}
+
+func loadIndexSnapshot(path string) (*memIndex, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	idx := newMemIndex()
+	if err := gob.NewDecoder(f).Decode(idx); err != nil {
+		return nil, err
+	}
+	return idx, nil
+}
+
+func saveIndexSnapshot(idx *memIndex, path string) error {
+	f, err := os.Create(path)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+	return gob.NewEncoder(f).Encode(idx)
+}
+
+// resetSearchIndex clears the in-memory index before loadDocs re-walks
+// content/, unless it was loaded from a snapshot.
+func resetSearchIndex() {
+	if skipSearchIndexing {
+		return
+	}
+	searchIdx = newMemIndex()
+}
+
+// searchHandler serves /search?q=... through template/search.tmpl.
+func searchHandler() http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		q := r.URL.Query().Get("q")
+		stateMu.Lock()
+		defer stateMu.Unlock()
+		var results []SearchResult
+		if q != "" {
+			results = searchIdx.Search(q)
+		}
+		args := struct {
+			Query   string
+			Results []SearchResult
+		}{q, results}
+		if err := searchTmpl.ExecuteTemplate(w, "root", args); err != nil {
+			http.Error(w, "failed to render search results", http.StatusInternalServerError)
+		}
+	})
+}
+
+const opensearchXML = `<?xml version="1.0" encoding="UTF-8"?>
+<OpenSearchDescription xmlns="http://a9.com/-/spec/opensearch/1.1/">
+  <ShortName>Gio</ShortName>
+  <Description>Search gioui.org</Description>
+  <Url type="text/html" method="get" template="https://gioui.org/search?q={searchTerms}"/>
+</OpenSearchDescription>
+`
+
+// opensearchHandler serves /opensearch.xml so browsers can auto-discover
+// site search.
+func opensearchHandler() http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/opensearchdescription+xml; charset=utf-8")
+		io.WriteString(w, opensearchXML)
+	})
+}
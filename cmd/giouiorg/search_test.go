@@ -0,0 +1,55 @@
+// SPDX-License-Identifier: Unlicense OR MIT
+
+package main
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestSplitQueryFields(t *testing.T) {
+	tests := []struct {
+		query string
+		want  []string
+	}{
+		{"", nil},
+		{"gio", []string{"gio"}},
+		{"gio layout", []string{"gio", "layout"}},
+		{`"gio layout"`, []string{`"gio layout"`}},
+		{`widget "event handling" clip`, []string{"widget", `"event handling"`, "clip"}},
+		{"a   b", []string{"a", "b"}},
+	}
+	for _, tt := range tests {
+		got := splitQueryFields(tt.query)
+		if !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("splitQueryFields(%q) = %#v, want %#v", tt.query, got, tt.want)
+		}
+	}
+}
+
+func TestParseQuery(t *testing.T) {
+	tests := []struct {
+		query string
+		want  []queryClause
+	}{
+		{"", nil},
+		{"gio", []queryClause{{termQuery, []string{"gio"}}}},
+		{"gio layout", []queryClause{
+			{termQuery, []string{"gio"}},
+			{termQuery, []string{"layout"}},
+		}},
+		{"gio OR layout", []queryClause{{orQuery, []string{"gio", "layout"}}}},
+		{"gio OR layout OR clip", []queryClause{{orQuery, []string{"gio", "layout", "clip"}}}},
+		{`"event handling"`, []queryClause{{phraseQuery, []string{"event", "handling"}}}},
+		{`widget "event handling"`, []queryClause{
+			{termQuery, []string{"widget"}},
+			{phraseQuery, []string{"event", "handling"}},
+		}},
+	}
+	for _, tt := range tests {
+		got := parseQuery(tt.query)
+		if !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("parseQuery(%q) = %#v, want %#v", tt.query, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,203 @@
+// SPDX-License-Identifier: Unlicense OR MIT
+
+package main
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"html/template"
+	"regexp"
+
+	"github.com/gomarkdown/markdown"
+)
+
+// shortcodeFunc renders one shortcode invocation. body is empty for
+// self-closing shortcodes, and for the {{% %}} block form has already been
+// rendered to HTML by the markdown pipeline.
+type shortcodeFunc func(args map[string]string, body string) (template.HTML, error)
+
+// shortcodes holds the built-ins from a var initializer, not an init func:
+// page.go's own init calls loadDocs, which expands shortcodes.
+var shortcodes = map[string]shortcodeFunc{
+	"code":    shortcodeCode,
+	"image":   shortcodeImage,
+	"figure":  shortcodeFigure,
+	"youtube": shortcodeYoutube,
+	"godoc":   shortcodeGodoc,
+}
+
+// RegisterShortcode makes a shortcode usable as {{< name ... >}} or
+// {{% name ... %}} in markdown content. It is exported so shortcodes can be
+// added without touching the core expansion loop.
+func RegisterShortcode(name string, fn shortcodeFunc) {
+	shortcodes[name] = fn
+}
+
+var shortcodeArgRE = regexp.MustCompile(`(\w+)="([^"]*)"`)
+
+// expandShortcodes replaces {{< name arg="val" >}}...{{< /name >}} and
+// {{% name %}}...{{% /name %}} shortcode invocations with the HTML their
+// registered handler returns. It runs before the markdown renderer sees the
+// content, mirroring Hugo's shortcode pass.
+//
+// Any failure (an unknown shortcode name, an unterminated closing tag, or
+// the shortcode's own fn returning an error) writes a visible
+// "shortcode-error" marker in place of that invocation, so a typo in one
+// shortcode never takes down the whole page. After an unknown name or an fn
+// error expansion carries on; after a missing closing tag the rest of the
+// content is emitted unexpanded.
+func expandShortcodes(src []byte, defaultHighlight string) []byte {
+	var out bytes.Buffer
+	for len(src) > 0 {
+		loc, open := findShortcodeOpen(src)
+		if loc == nil {
+			out.Write(src)
+			break
+		}
+		out.Write(src[:loc[0]])
+		name := string(src[loc[4]:loc[5]])
+		args := parseShortcodeArgs(string(src[loc[6]:loc[7]]))
+		selfClose := loc[9] > loc[8]
+		rest := src[loc[1]:]
+
+		fn, ok := shortcodes[name]
+		if !ok {
+			out.WriteString(shortcodeErrorHTML(name, fmt.Sprintf("unknown shortcode %q", name)))
+			src = rest
+			continue
+		}
+
+		var body string
+		if !selfClose {
+			closeRE := closeTagRE(open, name)
+			closeLoc := closeRE.FindIndex(rest)
+			if closeLoc == nil {
+				// There's no reliable way to know where this invocation
+				// would have ended, so the rest of the file is emitted
+				// verbatim rather than guessing.
+				out.WriteString(shortcodeErrorHTML(name, fmt.Sprintf("missing closing tag for shortcode %q", name)))
+				out.Write(rest)
+				return out.Bytes()
+			}
+			body = string(rest[:closeLoc[0]])
+			rest = rest[closeLoc[1]:]
+			if open == "%" {
+				body = string(markdown.ToHTML([]byte(body), nil, nil))
+			}
+		}
+
+		if _, ok := args["highlight"]; !ok && defaultHighlight != "" {
+			args["highlight"] = defaultHighlight
+		}
+
+		html, err := fn(args, body)
+		if err != nil {
+			out.WriteString(shortcodeErrorHTML(name, err.Error()))
+		} else {
+			out.WriteString(string(html))
+		}
+		src = rest
+	}
+	return out.Bytes()
+}
+
+func shortcodeErrorHTML(name, msg string) string {
+	return fmt.Sprintf(`<div class="shortcode-error">shortcode %q: %s</div>`, name, template.HTMLEscapeString(msg))
+}
+
+// findShortcodeOpen finds the next "{{<" or "{{%" opening tag and returns
+// FindSubmatchIndex-style offsets along with which opening delimiter
+// matched. "{{< ... >}}" closes with ">", not "<", so the opening and
+// closing characters are tracked separately via closeCharFor.
+func findShortcodeOpen(src []byte) ([]int, string) {
+	var best []int
+	bestOpen := ""
+	for _, open := range []string{"<", "%"} {
+		close := closeCharFor(open)
+		re := regexp.MustCompile(`\{\{` + regexp.QuoteMeta(open) + `\s*([\w-]+)((?:\s+\w+="[^"]*")*)\s*(/?)` + regexp.QuoteMeta(close) + `\}\}`)
+		if loc := re.FindSubmatchIndex(src); loc != nil && (best == nil || loc[0] < best[0]) {
+			// Normalize to the 5-group shape callers expect: whole match,
+			// an unused placeholder, name, args, self-close marker.
+			best = []int{loc[0], loc[1], 0, 0, loc[2], loc[3], loc[4], loc[5], loc[6], loc[7]}
+			bestOpen = open
+		}
+	}
+	return best, bestOpen
+}
+
+// closeCharFor returns the character that closes a shortcode tag opened
+// with open: "{{< ... >}}" pairs "<" with ">", while "{{% ... %}}" is
+// symmetric.
+func closeCharFor(open string) string {
+	if open == "<" {
+		return ">"
+	}
+	return open
+}
+
+func closeTagRE(open, name string) *regexp.Regexp {
+	o, c := regexp.QuoteMeta(open), regexp.QuoteMeta(closeCharFor(open))
+	return regexp.MustCompile(`\{\{` + o + `\s*/\s*` + regexp.QuoteMeta(name) + `\s*` + c + `\}\}`)
+}
+
+func parseShortcodeArgs(blob string) map[string]string {
+	args := make(map[string]string)
+	for _, m := range shortcodeArgRE.FindAllStringSubmatch(blob, -1) {
+		args[m[1]] = m[2]
+	}
+	return args
+}
+
+func shortcodeCode(args map[string]string, body string) (template.HTML, error) {
+	src, ok := args["src"]
+	if !ok {
+		return "", errors.New(`code shortcode: missing "src" argument`)
+	}
+	return highlightedInclude(src, args["addr"], args["highlight"])
+}
+
+func shortcodeImage(args map[string]string, body string) (template.HTML, error) {
+	src, ok := args["src"]
+	if !ok {
+		return "", errors.New(`image shortcode: missing "src" argument`)
+	}
+	return template.HTML(fmt.Sprintf(`<img src="%s" alt="%s">`,
+		template.HTMLEscapeString(src), template.HTMLEscapeString(args["alt"]))), nil
+}
+
+func shortcodeFigure(args map[string]string, body string) (template.HTML, error) {
+	src, ok := args["src"]
+	if !ok {
+		return "", errors.New(`figure shortcode: missing "src" argument`)
+	}
+	caption := body
+	if c, ok := args["caption"]; ok {
+		caption = template.HTMLEscapeString(c)
+	}
+	return template.HTML(fmt.Sprintf(`<figure><img src="%s" alt="%s"><figcaption>%s</figcaption></figure>`,
+		template.HTMLEscapeString(src), template.HTMLEscapeString(args["alt"]), caption)), nil
+}
+
+func shortcodeYoutube(args map[string]string, body string) (template.HTML, error) {
+	id, ok := args["id"]
+	if !ok {
+		return "", errors.New(`youtube shortcode: missing "id" argument`)
+	}
+	return template.HTML(fmt.Sprintf(
+		`<iframe src="https://www.youtube.com/embed/%s" allowfullscreen></iframe>`,
+		template.HTMLEscapeString(id))), nil
+}
+
+func shortcodeGodoc(args map[string]string, body string) (template.HTML, error) {
+	pkg, ok := args["pkg"]
+	if !ok {
+		return "", errors.New(`godoc shortcode: missing "pkg" argument`)
+	}
+	text := body
+	if text == "" {
+		text = template.HTMLEscapeString(pkg)
+	}
+	return template.HTML(fmt.Sprintf(`<a href="https://pkg.go.dev/%s">%s</a>`,
+		template.HTMLEscapeString(pkg), text)), nil
+}
@@ -0,0 +1,50 @@
+// SPDX-License-Identifier: Unlicense OR MIT
+
+package main
+
+import (
+	"errors"
+	"html/template"
+	"strings"
+	"testing"
+)
+
+var errTestShortcode = errors.New("shortcode_test: boom")
+
+func TestExpandShortcodesInlineForm(t *testing.T) {
+	// {{< >}} closes with ">", not "<"; this regressed to a no-op once
+	// before because findShortcodeOpen paired "<" with itself.
+	RegisterShortcode("noop-test", func(args map[string]string, body string) (template.HTML, error) {
+		return "", nil
+	})
+	got := string(expandShortcodes([]byte("hello {{< noop-test />}} world"), ""))
+	if got == "hello {{< noop-test />}} world" {
+		t.Fatalf("expandShortcodes did not expand the inline {{< >}} form: %q", got)
+	}
+	if got != "hello  world" {
+		t.Errorf("got %q, want %q", got, "hello  world")
+	}
+}
+
+func TestExpandShortcodesUnknownNameDoesNotAbortPage(t *testing.T) {
+	got := string(expandShortcodes([]byte("before {{< does-not-exist >}} after"), ""))
+	if !strings.Contains(got, "before ") || !strings.Contains(got, " after") {
+		t.Fatalf("unknown shortcode should not drop surrounding content: %q", got)
+	}
+	if !strings.Contains(got, "shortcode-error") {
+		t.Errorf("expected a visible shortcode-error marker, got %q", got)
+	}
+}
+
+func TestExpandShortcodesFnErrorDoesNotAbortPage(t *testing.T) {
+	RegisterShortcode("erroring-test", func(args map[string]string, body string) (template.HTML, error) {
+		return "", errTestShortcode
+	})
+	got := string(expandShortcodes([]byte("before {{< erroring-test />}} after"), ""))
+	if !strings.Contains(got, "before ") || !strings.Contains(got, " after") {
+		t.Fatalf("a failing shortcode should not drop surrounding content: %q", got)
+	}
+	if !strings.Contains(got, "shortcode-error") {
+		t.Errorf("expected a visible shortcode-error marker, got %q", got)
+	}
+}
@@ -0,0 +1,188 @@
+// SPDX-License-Identifier: Unlicense OR MIT
+
+package main
+
+import (
+	"bufio"
+	"bytes"
+	"html/template"
+	"net/http"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+)
+
+const (
+	tagsURL       = "/tags/"
+	categoriesURL = "/categories/"
+)
+
+// docInfo is the metadata recorded per page for taxonomy listings; it's
+// populated alongside the pages cache as loadDocs walks content/.
+type docInfo struct {
+	Path       string
+	Title      string
+	Excerpt    string
+	Tags       []string
+	Categories []string
+}
+
+var (
+	taxonomyTmpl *template.Template
+	termTmpl     *template.Template
+
+	// taxonomies maps a taxonomy name ("tags", "categories") to an inverted
+	// index of term -> the pages tagged with it.
+	taxonomies = make(map[string]map[string][]docInfo)
+)
+
+func init() {
+	taxonomyTmpl = template.Must(template.ParseFiles(
+		filepath.Join("template", "taxonomy.tmpl"),
+		filepath.Join("template", "root.tmpl"),
+	))
+	termTmpl = template.Must(template.ParseFiles(
+		filepath.Join("template", "term.tmpl"),
+		filepath.Join("template", "root.tmpl"),
+	))
+}
+
+func resetTaxonomies() {
+	taxonomies = map[string]map[string][]docInfo{
+		"tags":       make(map[string][]docInfo),
+		"categories": make(map[string][]docInfo),
+	}
+}
+
+// recordTaxonomies indexes a page's tags and categories; loadMarkdown calls
+// it once per output format a page has, so any previous entries for path
+// are dropped first to avoid duplicate listings.
+func recordTaxonomies(path string, front frontMatter, excerpt string) {
+	removeFromTaxonomies(path)
+	info := docInfo{Path: path, Title: front.Title, Excerpt: excerpt, Tags: front.Tags, Categories: front.Categories}
+	for _, tag := range front.Tags {
+		taxonomies["tags"][tag] = append(taxonomies["tags"][tag], info)
+	}
+	for _, cat := range front.Categories {
+		taxonomies["categories"][cat] = append(taxonomies["categories"][cat], info)
+	}
+}
+
+func removeFromTaxonomies(path string) {
+	for _, terms := range taxonomies {
+		for term, docs := range terms {
+			filtered := docs[:0]
+			for _, d := range docs {
+				if d.Path != path {
+					filtered = append(filtered, d)
+				}
+			}
+			terms[term] = filtered
+		}
+	}
+}
+
+// excerpt returns the first paragraph of a markdown body, for use in
+// taxonomy listings.
+func excerpt(content []byte) string {
+	var buf bytes.Buffer
+	scanner := bufio.NewScanner(bytes.NewReader(content))
+	started := false
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" {
+			if started {
+				break
+			}
+			continue
+		}
+		if strings.HasPrefix(line, "#") {
+			continue
+		}
+		started = true
+		buf.WriteString(line)
+		buf.WriteByte(' ')
+	}
+	return strings.TrimSpace(buf.String())
+}
+
+func sortedTerms(taxonomy string) []string {
+	terms := make([]string, 0, len(taxonomies[taxonomy]))
+	for term := range taxonomies[taxonomy] {
+		terms = append(terms, term)
+	}
+	sort.Strings(terms)
+	return terms
+}
+
+func sortedPages(pages []docInfo) []docInfo {
+	sorted := make([]docInfo, len(pages))
+	copy(sorted, pages)
+	sort.Slice(sorted, func(i, j int) bool { return sorted[i].Title < sorted[j].Title })
+	return sorted
+}
+
+// taxonomyHandler serves the /tags/, /tags/<term>/, /categories/ and
+// /categories/<term>/ listing pages, falling back for anything else.
+func taxonomyHandler(fallback http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		path := r.URL.Path
+		taxonomy, term, ok := parseTaxonomyPath(path)
+		if !ok {
+			fallback.ServeHTTP(w, r)
+			return
+		}
+		stateMu.Lock()
+		defer stateMu.Unlock()
+		if os.Getenv("GAE_APPLICATION") == "" {
+			if err := loadDocs(contentRoot); err != nil {
+				http.Error(w, "failed to rebuild taxonomy index", http.StatusInternalServerError)
+				return
+			}
+		}
+		if term == "" {
+			renderTaxonomy(w, taxonomy)
+		} else {
+			renderTerm(w, taxonomy, term)
+		}
+	})
+}
+
+func parseTaxonomyPath(path string) (taxonomy, term string, ok bool) {
+	for _, prefix := range []string{tagsURL, categoriesURL} {
+		if !strings.HasPrefix(path, prefix) {
+			continue
+		}
+		taxonomy = strings.TrimSuffix(prefix, "/")[1:]
+		term = strings.Trim(strings.TrimPrefix(path, prefix), "/")
+		return taxonomy, term, true
+	}
+	return "", "", false
+}
+
+func renderTaxonomy(w http.ResponseWriter, taxonomy string) {
+	args := struct {
+		Taxonomy string
+		Terms    []string
+	}{taxonomy, sortedTerms(taxonomy)}
+	if err := taxonomyTmpl.ExecuteTemplate(w, "root", args); err != nil {
+		http.Error(w, "failed to render taxonomy", http.StatusInternalServerError)
+	}
+}
+
+func renderTerm(w http.ResponseWriter, taxonomy, term string) {
+	pages, ok := taxonomies[taxonomy][term]
+	if !ok {
+		http.Error(w, "no such term", http.StatusNotFound)
+		return
+	}
+	args := struct {
+		Taxonomy string
+		Term     string
+		Pages    []docInfo
+	}{taxonomy, term, sortedPages(pages)}
+	if err := termTmpl.ExecuteTemplate(w, "root", args); err != nil {
+		http.Error(w, "failed to render term", http.StatusInternalServerError)
+	}
+}